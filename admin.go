@@ -8,6 +8,7 @@ import (
 	"log"
 	"os"
 	"os/user"
+	"strconv"
 	"strings"
 
 	"github.com/Shopify/sarama"
@@ -20,12 +21,28 @@ type adminCmd struct {
 	tlsCA      string
 	tlsCert    string
 	tlsCertKey string
+	auth       string
+
+	oauthTokenURL     string
+	oauthClientID     string
+	oauthClientSecret string
+	oauthScope        string
+	oauthTokenCmd     string
 
 	createTopic  string
 	topicDetail  *sarama.TopicDetail
 	validateOnly bool
 	deleteTopic  string
 
+	reassignPartitions  string
+	reassignTopic       string
+	reassignAssignments map[string][]int32
+	listReassignments   string
+
+	describeConfig string
+	alterConfig    string
+	configEntries  map[string]*string
+
 	admin sarama.ClusterAdmin
 }
 
@@ -36,11 +53,33 @@ type adminArgs struct {
 	tlsCA      string
 	tlsCert    string
 	tlsCertKey string
+	auth       string
+
+	oauthTokenURL     string
+	oauthClientID     string
+	oauthClientSecret string
+	oauthScope        string
+	oauthTokenCmd     string
 
 	createTopic     string
 	topicDetailPath string
 	validateOnly    bool
 	deleteTopic     string
+
+	reassignPartitions string
+	listReassignments  string
+
+	describeConfig    string
+	alterConfig       string
+	configEntriesPath string
+}
+
+// reassignPartitionsInput is the expected shape of the -reassignpartitions
+// input file. Assignments are keyed by partition number; a null broker list
+// cancels an in-progress reassignment for that partition.
+type reassignPartitionsInput struct {
+	Topic       string             `json:"topic"`
+	Assignments map[string][]int32 `json:"assignments"`
 }
 
 func (cmd *adminCmd) parseArgs(as []string) {
@@ -54,6 +93,17 @@ func (cmd *adminCmd) parseArgs(as []string) {
 	cmd.tlsCert = args.tlsCert
 	cmd.tlsCertKey = args.tlsCertKey
 
+	cmd.auth = args.auth
+	if cmd.auth == "" {
+		cmd.auth = os.Getenv("KT_AUTH")
+	}
+
+	cmd.oauthTokenURL = args.oauthTokenURL
+	cmd.oauthClientID = args.oauthClientID
+	cmd.oauthClientSecret = args.oauthClientSecret
+	cmd.oauthScope = args.oauthScope
+	cmd.oauthTokenCmd = args.oauthTokenCmd
+
 	envBrokers := os.Getenv("KT_BROKERS")
 	if args.brokers == "" {
 		if envBrokers != "" {
@@ -85,6 +135,63 @@ func (cmd *adminCmd) parseArgs(as []string) {
 		}
 		cmd.topicDetail = &detail
 	}
+
+	cmd.reassignPartitions = args.reassignPartitions
+	cmd.listReassignments = args.listReassignments
+
+	if cmd.reassignPartitions != "" {
+		buf, err := ioutil.ReadFile(cmd.reassignPartitions)
+		if err != nil {
+			failf("failed to read partition reassignment err=%v", err)
+		}
+
+		var input reassignPartitionsInput
+		if err = json.Unmarshal(buf, &input); err != nil {
+			failf("failed to unmarshal partition reassignment err=%v", err)
+		}
+		cmd.reassignTopic = input.Topic
+		cmd.reassignAssignments = input.Assignments
+
+		for p := range input.Assignments {
+			if _, err := strconv.Atoi(p); err != nil {
+				failf("failed to parse partition %q in reassignment assignments err=%v", p, err)
+			}
+		}
+	}
+
+	cmd.describeConfig = args.describeConfig
+	cmd.alterConfig = args.alterConfig
+
+	if cmd.alterConfig != "" {
+		buf, err := ioutil.ReadFile(args.configEntriesPath)
+		if err != nil {
+			failf("failed to read config entries err=%v", err)
+		}
+
+		if err = json.Unmarshal(buf, &cmd.configEntries); err != nil {
+			failf("failed to unmarshal config entries err=%v", err)
+		}
+	}
+}
+
+// parseConfigResource splits a "<type>/<name>" argument (e.g. "topic/orders",
+// "broker/1", "broker_logger/1") into a sarama.ConfigResourceType and name.
+func parseConfigResource(s string) (sarama.ConfigResourceType, string, error) {
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return 0, "", fmt.Errorf("expected <type>/<name>, got %q", s)
+	}
+
+	switch parts[0] {
+	case "topic":
+		return sarama.TopicResource, parts[1], nil
+	case "broker":
+		return sarama.BrokerResource, parts[1], nil
+	case "broker_logger":
+		return sarama.BrokerLoggerResource, parts[1], nil
+	default:
+		return 0, "", fmt.Errorf("unsupported config resource type %q, expected one of topic, broker, broker_logger", parts[0])
+	}
 }
 
 func (cmd *adminCmd) run(args []string) {
@@ -105,8 +212,16 @@ func (cmd *adminCmd) run(args []string) {
 
 	} else if cmd.deleteTopic != "" {
 		cmd.runDeleteTopic()
+	} else if cmd.reassignPartitions != "" {
+		cmd.runReassignPartitions()
+	} else if cmd.listReassignments != "" {
+		cmd.runListReassignments()
+	} else if cmd.describeConfig != "" {
+		cmd.runDescribeConfig()
+	} else if cmd.alterConfig != "" {
+		cmd.runAlterConfig()
 	} else {
-		failf("need to supply at least one sub-command of: createtopic, deletetopic")
+		failf("need to supply at least one sub-command of: createtopic, deletetopic, reassignpartitions, listreassignments, describeconfig, alterconfig")
 	}
 }
 
@@ -124,6 +239,116 @@ func (cmd *adminCmd) runDeleteTopic() {
 	}
 }
 
+func (cmd *adminCmd) runReassignPartitions() {
+	topics, err := cmd.admin.DescribeTopics([]string{cmd.reassignTopic})
+	if err != nil {
+		failf("failed to describe topic for reassignment err=%v", err)
+	}
+	if len(topics) != 1 {
+		failf("failed to find topic metadata for topic=%s", cmd.reassignTopic)
+	}
+
+	// sarama.AlterPartitionReassignments takes assignment per partition
+	// index 0..len(assignment)-1 unconditionally, and a nil replica list
+	// means "cancel in-progress reassignment" (cf KIP-455). So partitions
+	// the input file doesn't mention are seeded with their current replicas
+	// here rather than left nil, to avoid silently cancelling reassignments
+	// the operator never asked to touch.
+	assignment := make([][]int32, len(topics[0].Partitions))
+	for _, p := range topics[0].Partitions {
+		assignment[int(p.ID)] = p.Replicas
+	}
+	for p, brokers := range cmd.reassignAssignments {
+		partition, _ := strconv.Atoi(p)
+		if partition < 0 || partition >= len(assignment) {
+			failf("partition %d is out of range for topic=%s (has %d partitions)", partition, cmd.reassignTopic, len(assignment))
+		}
+		assignment[partition] = brokers
+	}
+
+	err = cmd.admin.AlterPartitionReassignments(cmd.reassignTopic, assignment)
+	if err != nil {
+		failf("failed to alter partition reassignments err=%v", err)
+	}
+}
+
+func (cmd *adminCmd) runListReassignments() {
+	status, err := cmd.admin.ListPartitionReassignments(cmd.listReassignments, nil)
+	if err != nil {
+		failf("failed to list partition reassignments err=%v", err)
+	}
+
+	for topic, partitions := range status {
+		for partition, s := range partitions {
+			out := map[string]interface{}{
+				"topic":            topic,
+				"partition":        partition,
+				"replicas":         s.Replicas,
+				"addingReplicas":   s.AddingReplicas,
+				"removingReplicas": s.RemovingReplicas,
+			}
+			buf, err := json.Marshal(out)
+			if err != nil {
+				failf("failed to marshal partition reassignment status err=%v", err)
+			}
+			fmt.Println(string(buf))
+		}
+	}
+}
+
+func (cmd *adminCmd) runDescribeConfig() {
+	resourceType, name, err := parseConfigResource(cmd.describeConfig)
+	if err != nil {
+		failf("failed to parse describeconfig argument err=%v", err)
+	}
+
+	entries, err := cmd.admin.DescribeConfig(sarama.ConfigResource{Type: resourceType, Name: name})
+	if err != nil {
+		failf("failed to describe config err=%v", err)
+	}
+
+	for _, e := range entries {
+		out := map[string]interface{}{
+			"Name":      e.Name,
+			"Value":     e.Value,
+			"Source":    e.Source,
+			"Sensitive": e.Sensitive,
+			"ReadOnly":  e.ReadOnly,
+			"Synonyms":  e.Synonyms,
+		}
+		buf, err := json.Marshal(out)
+		if err != nil {
+			failf("failed to marshal config entry err=%v", err)
+		}
+		fmt.Println(string(buf))
+	}
+}
+
+func (cmd *adminCmd) runAlterConfig() {
+	resourceType, name, err := parseConfigResource(cmd.alterConfig)
+	if err != nil {
+		failf("failed to parse alterconfig argument err=%v", err)
+	}
+
+	// IncrementalAlterConfig applies a targeted SET/DELETE per key. The
+	// legacy AlterConfig replaces the resource's entire set of dynamic
+	// overrides with exactly the entries given, silently resetting anything
+	// not mentioned -- not what -configentries with a single key implies.
+	entries := make(map[string]sarama.IncrementalAlterConfigsEntry, len(cmd.configEntries))
+	for k, v := range cmd.configEntries {
+		if v == nil {
+			entries[k] = sarama.IncrementalAlterConfigsEntry{Operation: sarama.IncrementalAlterConfigsOperationDelete}
+		} else {
+			entries[k] = sarama.IncrementalAlterConfigsEntry{Operation: sarama.IncrementalAlterConfigsOperationSet, Value: v}
+		}
+	}
+
+	err = cmd.admin.IncrementalAlterConfig(resourceType, name, entries, cmd.validateOnly)
+	if err != nil {
+		failf("failed to alter config err=%v", err)
+	}
+}
+
 func (cmd *adminCmd) saramaConfig() *sarama.Config {
 	var (
 		err error
@@ -146,6 +371,14 @@ func (cmd *adminCmd) saramaConfig() *sarama.Config {
 		cfg.Net.TLS.Config = tlsConfig
 	}
 
+	if err = setupAuth(cmd.auth, cfg); err != nil {
+		failf("failed to setup auth err=%v", err)
+	}
+
+	if err = setupOAuth(cmd.oauthTokenURL, cmd.oauthClientID, cmd.oauthClientSecret, cmd.oauthScope, cmd.oauthTokenCmd, cfg); err != nil {
+		failf("failed to setup oauth err=%v", err)
+	}
+
 	return cfg
 }
 
@@ -158,13 +391,27 @@ func (cmd *adminCmd) parseFlags(as []string) adminArgs {
 	flags.StringVar(&args.tlsCA, "tlsca", "", "Path to the TLS certificate authority file")
 	flags.StringVar(&args.tlsCert, "tlscert", "", "Path to the TLS client certificate file")
 	flags.StringVar(&args.tlsCertKey, "tlscertkey", "", "Path to the TLS client certificate key file")
+	flags.StringVar(&args.auth, "auth", "", "Path to a JSON file with SASL credentials, cf KT_AUTH. Supports PLAIN, SCRAM-SHA-256, SCRAM-SHA-512 and OAUTHBEARER mechanisms.")
+
+	flags.StringVar(&args.oauthTokenURL, "oauth-token-url", "", "Token endpoint for an RFC 6749 client-credentials grant, enables OAUTHBEARER.")
+	flags.StringVar(&args.oauthClientID, "oauth-client-id", "", "Client id for -oauth-token-url.")
+	flags.StringVar(&args.oauthClientSecret, "oauth-client-secret", "", "Client secret for -oauth-token-url.")
+	flags.StringVar(&args.oauthScope, "oauth-scope", "", "Optional scope to request alongside -oauth-token-url.")
+	flags.StringVar(&args.oauthTokenCmd, "oauth-token-cmd", "", "External command whose stdout is used as the OAUTHBEARER token, e.g. \"aws msk-iam-sasl-signer ...\". Mutually exclusive with -oauth-token-url.")
 
 	flags.StringVar(&args.createTopic, "createtopic", "", "Name of the topic that should be created.")
 	flags.StringVar(&args.topicDetailPath, "topicdetail", "", "Path to JSON encoded topic detail. cf sarama.TopicDetail")
-	flags.BoolVar(&args.validateOnly, "validateonly", false, "Flag to indicate whether operation should only validate input (supported for createtopic).")
+	flags.BoolVar(&args.validateOnly, "validateonly", false, "Flag to indicate whether operation should only validate input (supported for createtopic and alterconfig).")
 
 	flags.StringVar(&args.deleteTopic, "deletetopic", "", "Name of the topic that should be deleted.")
 
+	flags.StringVar(&args.reassignPartitions, "reassignpartitions", "", "Path to JSON encoded partition reassignment, cf KIP-455.")
+	flags.StringVar(&args.listReassignments, "listreassignments", "", "Name of the topic to list in-progress partition reassignments for.")
+
+	flags.StringVar(&args.describeConfig, "describeconfig", "", "Resource to describe config for, as <type>/<name> (type is topic, broker or broker_logger).")
+	flags.StringVar(&args.alterConfig, "alterconfig", "", "Resource to alter config for, as <type>/<name> (type is topic, broker or broker_logger).")
+	flags.StringVar(&args.configEntriesPath, "configentries", "", "Path to JSON encoded map of config entries to set for -alterconfig. A null value deletes the override.")
+
 	flags.Usage = func() {
 		fmt.Fprintln(os.Stderr, "Usage of admin:")
 		flags.PrintDefaults()
@@ -185,8 +432,46 @@ var adminDocString = `
 The value for -brokers can also be set via environment variables KT_BROKERS.
 The value supplied on the command line wins over the environment variable value.
 
+The value for -auth can also be set via environment variable KT_AUTH.
+The credential file is JSON of the form:
+
+{"sasl_mechanism": "SCRAM-SHA-256", "sasl_username": "user", "sasl_password": "pass"}
+
+sasl_mechanism defaults to PLAIN when omitted.
+
+-oauth-token-url (with -oauth-client-id/-oauth-client-secret/-oauth-scope) or
+-oauth-token-cmd configure an OAUTHBEARER token provider. A token from
+-oauth-token-url is cached and refreshed transparently 30s before expiry;
+-oauth-token-cmd is re-run for every token. Use -oauth-token-cmd against brokers with
+their own token helper, e.g. MSK IAM via "aws msk-iam-sasl-signer ...".
+
+-auth is only wired into kt admin for now: kt produce/consume/group/topic
+aren't part of this tree yet, so they don't accept it. setupAuth (cf
+auth.go) is written to be called from any subcommand's config builder the
+same way it's called here, once those commands land.
+
+The -oauth-* flags have the same limitation: setupOAuth (cf oauth.go) is
+only called from kt admin's saramaConfig so far.
+
 If both -createtopic and deletetopic are supplied, -createtopic wins.
 
+The partition reassignment file passed to -reassignpartitions should be JSON of the form:
+
+{"topic": "foo", "assignments": {"0": [1,2,3], "1": [2,3,4]}}
+
+Assignments are keyed by partition number; a null broker list cancels an in-progress
+reassignment for that partition. Partitions left out of assignments are untouched --
+kt looks up their current replicas rather than cancelling any pending reassignment
+on them.
+
+The config entries file passed to -alterconfig via -configentries should be JSON of the form:
+
+{"retention.ms": "3600000", "cleanup.policy": null}
+
+Each key is applied as an incremental SET, and a null value as an incremental
+DELETE, resetting just that override to its default. Overrides on keys not
+present in the file are left untouched.
+
 The topic details should be passed via a JSON file that represents a sarama.TopicDetail struct.
 cf https://godoc.org/github.com/Shopify/sarama#TopicDetail
 
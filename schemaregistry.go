@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const confluentMagicByte = 0x00
+
+// schemaRegistryClient is a minimal client for the Confluent Schema Registry
+// REST API. It resolves and caches schemas referenced by the Confluent wire
+// format, cf the (blocked, see decodeRegistryValue) -registry flag on
+// produce/consume.
+type schemaRegistryClient struct {
+	url  string
+	user string
+	pass string
+	http *http.Client
+
+	mu      sync.Mutex
+	schemas map[int]string // schema id -> raw schema text
+}
+
+func newSchemaRegistryClient(url, user, pass, caPath string) (*schemaRegistryClient, error) {
+	tlsConfig, err := setupCerts("", caPath, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup registry certificates err=%v", err)
+	}
+
+	transport := &http.Transport{}
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	return &schemaRegistryClient{
+		url:     strings.TrimRight(url, "/"),
+		user:    user,
+		pass:    pass,
+		http:    &http.Client{Transport: transport},
+		schemas: map[int]string{},
+	}, nil
+}
+
+func (c *schemaRegistryClient) get(path string, out interface{}) error {
+	req, err := http.NewRequest("GET", c.url+path, nil)
+	if err != nil {
+		return err
+	}
+	if c.user != "" {
+		req.SetBasicAuth(c.user, c.pass)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("registry request to %s failed status=%d", path, resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// schemaByID fetches (and caches for the lifetime of the process) the raw
+// schema registered under id.
+func (c *schemaRegistryClient) schemaByID(id int) (string, error) {
+	c.mu.Lock()
+	if s, ok := c.schemas[id]; ok {
+		c.mu.Unlock()
+		return s, nil
+	}
+	c.mu.Unlock()
+
+	var out struct {
+		Schema string `json:"schema"`
+	}
+	if err := c.get(fmt.Sprintf("/schemas/ids/%d", id), &out); err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	c.schemas[id] = out.Schema
+	c.mu.Unlock()
+
+	return out.Schema, nil
+}
+
+// schemaBySubject resolves the schema id and raw schema registered for
+// subject at version ("latest" or a version number), caching the result.
+func (c *schemaRegistryClient) schemaBySubject(subject, version string) (int, string, error) {
+	if version == "" {
+		version = "latest"
+	}
+
+	var out struct {
+		ID     int    `json:"id"`
+		Schema string `json:"schema"`
+	}
+	if err := c.get(fmt.Sprintf("/subjects/%s/versions/%s", subject, version), &out); err != nil {
+		return 0, "", err
+	}
+
+	c.mu.Lock()
+	c.schemas[out.ID] = out.Schema
+	c.mu.Unlock()
+
+	return out.ID, out.Schema, nil
+}
+
+// decodeConfluentEnvelope splits the Confluent wire format (a leading magic
+// byte, a 4-byte big-endian schema id, then the encoded payload) used by
+// Avro/Protobuf/JSON-Schema messages produced via a schema registry.
+func decodeConfluentEnvelope(buf []byte) (int, []byte, error) {
+	if len(buf) < 5 || buf[0] != confluentMagicByte {
+		return 0, nil, fmt.Errorf("value does not carry a Confluent schema registry envelope")
+	}
+	id := int(binary.BigEndian.Uint32(buf[1:5]))
+	return id, buf[5:], nil
+}
+
+// encodeConfluentEnvelope prepends the Confluent magic byte and schema id to
+// an already-encoded payload.
+func encodeConfluentEnvelope(schemaID int, payload []byte) []byte {
+	buf := make([]byte, 5+len(payload))
+	buf[0] = confluentMagicByte
+	binary.BigEndian.PutUint32(buf[1:5], uint32(schemaID))
+	copy(buf[5:], payload)
+	return buf
+}
+
+// decodeRegistryValue decodes a schema-registry-framed value to JSON, adding
+// a schema_id field. Avro and Protobuf payloads require codecs (cf
+// linkedin/goavro and descriptor-set based decoding, respectively); JSON-Schema
+// payloads are passed through as-is since they're already JSON on the wire.
+//
+// BLOCKED: this is as far as the -registry deliverable goes in this tree.
+// Wiring it into `kt consume`/`kt produce` -- decoding on consume, encoding
+// via encodeConfluentEnvelope on produce, with a -registry flag analogous to
+// -auth in adminCmd.saramaConfig -- needs produce.go/consume.go, and neither
+// file exists in this tree snapshot (there's no -registry flag or command to
+// hang it off). schemaRegistryClient, the envelope codec, and the
+// JSON-Schema decode path above are the reusable groundwork for that wiring;
+// landing it for real is left for whoever adds produce.go/consume.go.
+func (c *schemaRegistryClient) decodeRegistryValue(buf []byte) (map[string]interface{}, error) {
+	id, payload, err := decodeConfluentEnvelope(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := c.schemaByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch schema id=%d err=%v", id, err)
+	}
+
+	var out map[string]interface{}
+	trimmed := strings.TrimSpace(schema)
+	switch {
+	case strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "["):
+		// Avro and JSON-Schema both register JSON schema text; without an
+		// Avro codec we can only decode the JSON-Schema case, where the
+		// payload itself is already JSON.
+		if err := json.Unmarshal(payload, &out); err != nil {
+			return nil, fmt.Errorf("failed to decode JSON-Schema payload for schema id=%d err=%v", id, err)
+		}
+	default:
+		return nil, fmt.Errorf("decoding schema id=%d requires an Avro/Protobuf codec not available in this build", id)
+	}
+
+	out["schema_id"] = id
+	return out, nil
+}
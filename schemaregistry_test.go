@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecodeConfluentEnvelopeRoundTrip(t *testing.T) {
+	payload := []byte(`{"hello":"world"}`)
+	buf := encodeConfluentEnvelope(7, payload)
+
+	id, decoded, err := decodeConfluentEnvelope(buf)
+	require.NoError(t, err)
+	require.Equal(t, 7, id)
+	require.Equal(t, payload, decoded)
+}
+
+func TestDecodeConfluentEnvelopeRejectsUnframedInput(t *testing.T) {
+	_, _, err := decodeConfluentEnvelope([]byte{0x01, 0x02})
+	require.Error(t, err)
+
+	_, _, err = decodeConfluentEnvelope([]byte{0x01, 0x00, 0x00, 0x00, 0x01, 'x'})
+	require.Error(t, err)
+}
+
+func TestSchemaRegistryClientSchemaByIDCachesResult(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		require.Equal(t, "/schemas/ids/7", r.URL.Path)
+		_ = json.NewEncoder(w).Encode(map[string]string{"schema": `{"type":"object"}`})
+	}))
+	defer srv.Close()
+
+	c, err := newSchemaRegistryClient(srv.URL, "", "", "")
+	require.NoError(t, err)
+
+	schema, err := c.schemaByID(7)
+	require.NoError(t, err)
+	require.Equal(t, `{"type":"object"}`, schema)
+
+	_, err = c.schemaByID(7)
+	require.NoError(t, err)
+	require.Equal(t, 1, requests, "expected the second schemaByID call to be served from cache")
+}
+
+func TestSchemaRegistryClientSchemaBySubjectDefaultsToLatest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/subjects/orders-value/versions/latest", r.URL.Path)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"id": 3, "schema": `{"type":"object"}`})
+	}))
+	defer srv.Close()
+
+	c, err := newSchemaRegistryClient(srv.URL, "", "", "")
+	require.NoError(t, err)
+
+	id, schema, err := c.schemaBySubject("orders-value", "")
+	require.NoError(t, err)
+	require.Equal(t, 3, id)
+	require.Equal(t, `{"type":"object"}`, schema)
+}
+
+func TestSchemaRegistryClientPropagatesHTTPErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not found", http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	c, err := newSchemaRegistryClient(srv.URL, "", "", "")
+	require.NoError(t, err)
+
+	_, err = c.schemaByID(99)
+	require.Error(t, err)
+}
+
+func TestDecodeRegistryValuePassesThroughJSONSchemaPayload(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{"schema": `{"type":"object"}`})
+	}))
+	defer srv.Close()
+
+	c, err := newSchemaRegistryClient(srv.URL, "", "", "")
+	require.NoError(t, err)
+
+	buf := encodeConfluentEnvelope(5, []byte(`{"value":"hi"}`))
+	out, err := c.decodeRegistryValue(buf)
+	require.NoError(t, err)
+	require.Equal(t, "hi", out["value"])
+	require.Equal(t, 5, out["schema_id"])
+}
+
+func TestDecodeRegistryValueRejectsUnframedInput(t *testing.T) {
+	c := &schemaRegistryClient{schemas: map[int]string{}}
+	_, err := c.decodeRegistryValue([]byte("not framed"))
+	require.Error(t, err)
+}
@@ -11,9 +11,17 @@ import (
 	"syscall"
 	"testing"
 
+	"github.com/Shopify/sarama"
 	"github.com/stretchr/testify/require"
+
+	"github.com/ejpearson/kt/testfixture"
 )
 
+// testBrokers is set per-subtest by TestSystem before exercising the cmd
+// helpers below, pointing them at that subtest's fixture brokers instead of
+// an externally managed cluster.
+var testBrokers = "localhost:9092"
+
 type cmd struct {
 	in string
 }
@@ -27,7 +35,7 @@ func (c *cmd) run(name string, args ...string) (int, string, string) {
 	cmd.Stdout = &stdOut
 	cmd.Stderr = &stdErr
 	cmd.Env = os.Environ()
-	cmd.Env = append(cmd.Env, "KT_BROKERS=localhost:9092")
+	cmd.Env = append(cmd.Env, "KT_BROKERS="+testBrokers)
 
 	if len(c.in) > 0 {
 		cmd.Stdin = strings.NewReader(c.in)
@@ -56,9 +64,28 @@ func testRequestData() map[string]interface{} {
 	}
 }
 
+// kafkaVersionsUnderTest are the protocol versions TestSystem matrices over,
+// so regressions against older brokers (e.g. KIP-482 tagged-field handling)
+// are caught before release rather than only against whatever's newest.
+var kafkaVersionsUnderTest = []sarama.KafkaVersion{
+	sarama.V2_1_1_0,
+	sarama.V2_8_2_0,
+	sarama.V3_3_1_0,
+}
+
 func TestSystem(t *testing.T) {
 	build(t)
 
+	for _, version := range kafkaVersionsUnderTest {
+		version := version
+		t.Run(version.String(), func(t *testing.T) {
+			testBrokers = testfixture.Start(t, version, "kt-test")
+			runSystemTest(t, version.String())
+		})
+	}
+}
+
+func runSystemTest(t *testing.T, version string) {
 	var err error
 	var status int
 	var stdOut, stdErr string
@@ -70,7 +97,7 @@ func TestSystem(t *testing.T) {
 	req := testRequestData()
 	buf, err := json.Marshal(req)
 	require.NoError(t, err)
-	status, stdOut, stdErr = newCmd().stdIn(string(buf)).run("./kt", "produce", "-topic", "kt-test")
+	status, stdOut, stdErr = newCmd().stdIn(string(buf)).run("./kt", "produce", "-topic", "kt-test", "-version", version)
 	fmt.Printf(">> system test kt produce stdout:\n%s\n", stdOut)
 	fmt.Printf(">> system test kt produce stderr:\n%s\n", stdErr)
 	require.Zero(t, status)
@@ -88,7 +115,7 @@ func TestSystem(t *testing.T) {
 	//
 	var lastConsumed map[string]interface{}
 
-	status, stdOut, stdErr = newCmd().run("./kt", "consume", "-topic", "kt-test", "-timeout", "500ms")
+	status, stdOut, stdErr = newCmd().run("./kt", "consume", "-topic", "kt-test", "-timeout", "500ms", "-version", version)
 	require.Zero(t, status)
 
 	lines := strings.Split(stdOut, "\n")
@@ -112,14 +139,14 @@ func TestSystem(t *testing.T) {
 	require.NoError(t, err)
 	mReqs += fmt.Sprintf("\n%s", buf)
 
-	status, stdOut, stdErr = newCmd().stdIn(mReqs).run("./kt", "produce", "-topic", "kt-test")
+	status, stdOut, stdErr = newCmd().stdIn(mReqs).run("./kt", "produce", "-topic", "kt-test", "-version", version)
 	fmt.Printf(">> system test kt produce stdout:\n%s\n", stdOut)
 	fmt.Printf(">> system test kt produce stderr:\n%s\n", stdErr)
 	require.Zero(t, status)
 	require.Empty(t, stdErr)
 
 	testGroup := randomString(8)
-	status, stdOut, stdErr = newCmd().run("./kt", "consume", "-topic", "kt-test", "-timeout", "500ms", "-group", testGroup)
+	status, stdOut, stdErr = newCmd().run("./kt", "consume", "-topic", "kt-test", "-timeout", "500ms", "-group", testGroup, "-version", version)
 	require.Zero(t, status)
 
 	lines = strings.Split(stdOut, "\n")
@@ -148,13 +175,13 @@ func TestSystem(t *testing.T) {
 	require.NoError(t, err)
 	mReqs += fmt.Sprintf("\n%s", buf)
 
-	status, stdOut, stdErr = newCmd().stdIn(mReqs).run("./kt", "produce", "-topic", "kt-test")
+	status, stdOut, stdErr = newCmd().stdIn(mReqs).run("./kt", "produce", "-topic", "kt-test", "-version", version)
 	fmt.Printf(">> system test kt produce stdout:\n%s\n", stdOut)
 	fmt.Printf(">> system test kt produce stderr:\n%s\n", stdErr)
 	require.Zero(t, status)
 	require.Empty(t, stdErr)
 
-	status, stdOut, stdErr = newCmd().run("./kt", "consume", "-topic", "kt-test", "-timeout", "500ms", "-group", testGroup)
+	status, stdOut, stdErr = newCmd().run("./kt", "consume", "-topic", "kt-test", "-timeout", "500ms", "-group", testGroup, "-version", version)
 	require.Zero(t, status)
 
 	lines = strings.Split(stdOut, "\n")
@@ -175,7 +202,7 @@ func TestSystem(t *testing.T) {
 	// kt group
 	//
 
-	status, stdOut, stdErr = newCmd().run("./kt", "group", "-topic", "kt-test")
+	status, stdOut, stdErr = newCmd().run("./kt", "group", "-topic", "kt-test", "-version", version)
 	require.Zero(t, status)
 	require.Contains(t, stdErr, "found partitions=[0] for topic=kt-test")
 	require.Empty(t, stdOut)
@@ -184,7 +211,7 @@ func TestSystem(t *testing.T) {
 	// kt group reset
 	//
 
-	status, stdOut, stdErr = newCmd().run("./kt", "group", "-topic", "kt-test", "-partitions", "0", "-group", "hans", "-reset", "1")
+	status, stdOut, stdErr = newCmd().run("./kt", "group", "-topic", "kt-test", "-partitions", "0", "-group", "hans", "-reset", "1", "-version", version)
 	require.Zero(t, status)
 
 	lines = strings.Split(stdOut, "\n")
@@ -205,7 +232,7 @@ func TestSystem(t *testing.T) {
 	// kt topic
 	//
 
-	status, stdOut, stdErr = newCmd().run("./kt", "topic")
+	status, stdOut, stdErr = newCmd().run("./kt", "topic", "-version", version)
 	require.Zero(t, status)
 	require.Empty(t, stdErr)
 